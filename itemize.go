@@ -0,0 +1,136 @@
+package rsyncparse
+
+import "regexp"
+
+// UpdateKind identifies the kind of update described by an ItemChange, i.e.
+// the first ("Y") character of rsync's itemize-changes output.
+type UpdateKind int
+
+const (
+	// UpdateUnknown is the zero value and is never produced by
+	// ParseItemizeLine.
+	UpdateUnknown UpdateKind = iota
+	// UpdateTransferSent indicates the file was sent to the remote host
+	// ('<').
+	UpdateTransferSent
+	// UpdateTransferReceived indicates the file was received from the
+	// remote host ('>').
+	UpdateTransferReceived
+	// UpdateLocalChange indicates a local change or creation, e.g. a
+	// symlink, device node or changed directory ('c').
+	UpdateLocalChange
+	// UpdateHardlink indicates the item is a hard link to another item
+	// in the transfer ('h').
+	UpdateHardlink
+	// UpdateNotTransferred indicates the item itself was not updated,
+	// though its attributes (see ItemChange's flag fields) may have been
+	// ('.').
+	UpdateNotTransferred
+	// UpdateDeleting indicates the item was deleted from the destination
+	// because --delete was given ('*deleting').
+	UpdateDeleting
+)
+
+// ItemChange is a single parsed line of rsync --itemize-changes (-i) output.
+type ItemChange struct {
+	Update UpdateKind
+	// FileType is the second ("X") character of the itemized output: one
+	// of 'f' (file), 'd' (directory), 'L' (symlink), 'D' (device) or 'S'
+	// (special file). It is the zero byte for UpdateDeleting, since
+	// rsync does not report a file type for deletions.
+	FileType byte
+
+	// The following report which attributes differ from the
+	// destination, as indicated by the 9 single-character flags
+	// following the update type and file type ("cstpoguax"). They are
+	// always false for UpdateDeleting.
+	Checksum    bool
+	Size        bool
+	Time        bool
+	Permissions bool
+	Owner       bool
+	Group       bool
+	ACL         bool
+	Xattr       bool
+
+	// New is true if every flag position reported '+', which is how
+	// rsync marks an item that does not yet exist on the receiving side.
+	New bool
+
+	Path string
+}
+
+var (
+	itemizeRe  = regexp.MustCompile(`^([<>ch.])([fdLDS])([cstpoguax.+?]{9}) (.*)$`)
+	deletingRe = regexp.MustCompile(`^\*deleting\s+(.*)$`)
+)
+
+// ParseItemizeLine parses a single line of rsync --itemize-changes (-i)
+// output into an ItemChange. It reports false if line does not look like
+// itemized output at all (for example because --out-format was used to
+// print something else), so that callers can fall back to treating it as a
+// regular summary or progress line.
+func ParseItemizeLine(line string) (ItemChange, bool) {
+	if m := deletingRe.FindStringSubmatch(line); m != nil {
+		return ItemChange{Update: UpdateDeleting, Path: m[1]}, true
+	}
+
+	m := itemizeRe.FindStringSubmatch(line)
+	if m == nil {
+		return ItemChange{}, false
+	}
+
+	ic := ItemChange{FileType: m[2][0], Path: m[4]}
+	switch m[1] {
+	case "<":
+		ic.Update = UpdateTransferSent
+	case ">":
+		ic.Update = UpdateTransferReceived
+	case "c":
+		ic.Update = UpdateLocalChange
+	case "h":
+		ic.Update = UpdateHardlink
+	case ".":
+		ic.Update = UpdateNotTransferred
+	}
+
+	flags := m[3]
+	ic.New = flags == "+++++++++"
+	if !ic.New {
+		changed := func(i int) bool { return flags[i] != '.' }
+		ic.Checksum = changed(0)
+		ic.Size = changed(1)
+		ic.Time = changed(2)
+		ic.Permissions = changed(3)
+		ic.Owner = changed(4)
+		ic.Group = changed(5)
+		// flags[6] ('u') is reserved by rsync and currently always '.'.
+		ic.ACL = changed(7)
+		ic.Xattr = changed(8)
+	}
+
+	return ic, true
+}
+
+// applyItemChange folds a single ItemChange into the running per-transfer
+// counts on p.
+func (p *Stats) applyItemChange(ic ItemChange) {
+	switch {
+	case ic.Update == UpdateDeleting:
+		p.FilesDeleted++
+	case ic.FileType == 'd':
+		p.DirsChanged++
+	case ic.FileType == 'L':
+		p.SymlinksChanged++
+	case ic.Update == UpdateTransferSent, ic.Update == UpdateTransferReceived, ic.Update == UpdateHardlink:
+		if ic.New {
+			p.FilesCreated++
+		} else {
+			p.FilesUpdated++
+		}
+	case ic.Update == UpdateLocalChange:
+		p.FilesUpdated++
+	case ic.Update == UpdateNotTransferred:
+		p.FilesUpdated++
+	}
+}
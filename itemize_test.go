@@ -0,0 +1,99 @@
+package rsyncparse
+
+import "testing"
+
+func TestParseItemizeLine(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		line string
+		want ItemChange
+		ok   bool
+	}{
+		{
+			name: "new regular file",
+			line: ">f+++++++++ some/new/file.txt",
+			want: ItemChange{Update: UpdateTransferReceived, FileType: 'f', New: true, Path: "some/new/file.txt"},
+			ok:   true,
+		},
+		{
+			name: "updated file, size and time changed",
+			line: ">f.st...... existing/file.txt",
+			want: ItemChange{Update: UpdateTransferReceived, FileType: 'f', Size: true, Time: true, Path: "existing/file.txt"},
+			ok:   true,
+		},
+		{
+			name: "new directory",
+			line: "cd+++++++++ some/new/dir",
+			want: ItemChange{Update: UpdateLocalChange, FileType: 'd', New: true, Path: "some/new/dir"},
+			ok:   true,
+		},
+		{
+			name: "attrs-only, permissions changed",
+			line: ".f..tp..... path/with spaces/file",
+			want: ItemChange{Update: UpdateNotTransferred, FileType: 'f', Time: true, Permissions: true, Path: "path/with spaces/file"},
+			ok:   true,
+		},
+		{
+			name: "hardlink",
+			line: "hf+++++++++ linked/file",
+			want: ItemChange{Update: UpdateHardlink, FileType: 'f', New: true, Path: "linked/file"},
+			ok:   true,
+		},
+		{
+			name: "deleting",
+			line: "*deleting   stale/file",
+			want: ItemChange{Update: UpdateDeleting, Path: "stale/file"},
+			ok:   true,
+		},
+		{
+			name: "non-itemize line falls back",
+			line: "sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec",
+			ok:   false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseItemizeLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseItemizeLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAccumulatesItemizeCounts(t *testing.T) {
+	p := &Stats{}
+	lines := []string{
+		">f+++++++++ new-file",
+		">f.st...... changed-file",
+		"cd+++++++++ new-dir",
+		"cL+++++++++ new-symlink",
+		"*deleting   removed-file",
+		".f..tp..... attrs-only-file",
+	}
+	for _, line := range lines {
+		if err := p.parseLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := p.FilesCreated, int64(1); got != want {
+		t.Errorf("FilesCreated = %d, want %d", got, want)
+	}
+	if got, want := p.FilesUpdated, int64(2); got != want {
+		t.Errorf("FilesUpdated = %d, want %d", got, want)
+	}
+	if got, want := p.DirsChanged, int64(1); got != want {
+		t.Errorf("DirsChanged = %d, want %d", got, want)
+	}
+	if got, want := p.SymlinksChanged, int64(1); got != want {
+		t.Errorf("SymlinksChanged = %d, want %d", got, want)
+	}
+	if got, want := p.FilesDeleted, int64(1); got != want {
+		t.Errorf("FilesDeleted = %d, want %d", got, want)
+	}
+}
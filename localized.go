@@ -0,0 +1,389 @@
+package rsyncparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NumberLocale selects how the digit groups and decimal points in rsync's
+// numbers are to be interpreted. rsync formats numbers using the process
+// locale unless it is started with LC_ALL=C (or C.UTF-8).
+type NumberLocale int
+
+const (
+	// LocaleC expects "," as the thousands separator and "." as the
+	// decimal point, e.g. "1,188,046.00". This is what Parse assumes.
+	LocaleC NumberLocale = iota
+	// LocaleDotComma expects "." as the thousands separator and "," as
+	// the decimal point, e.g. "1.188.046,00" (de_DE, fr_FR and most
+	// other European locales glibc ships).
+	LocaleDotComma
+	// LocaleSpaceComma expects a space (in practice, glibc emits a
+	// Unicode NBSP, thin space or narrow no-break space) as the
+	// thousands separator and "," as the decimal point, e.g.
+	// "1 188 046,00".
+	LocaleSpaceComma
+)
+
+// HumanReadableMode selects how to interpret rsync's --human-readable (-h)
+// number suffixes. The level-to-base mapping below follows rsync's
+// documented --human-readable levels (repeat -h, or pass -hh, to go from
+// level 1 to level 2); it has not been checked against a captured real
+// rsync invocation, since no rsync binary is available in the environment
+// this package was developed in. Please file an issue if a real -hh
+// capture turns up a different base.
+type HumanReadableMode int
+
+const (
+	// HumanReadableOff expects plain numbers with no unit suffix, as
+	// Parse assumes.
+	HumanReadableOff HumanReadableMode = iota
+	// HumanReadableBinary interprets a trailing K/M/G/T suffix as a
+	// 1024-based multiplier, as rsync's do_big_num emits with a single
+	// -h.
+	HumanReadableBinary
+	// HumanReadableSI interprets a trailing K/M/G/T suffix as a
+	// 1000-based multiplier, as rsync's do_big_num emits with -hh.
+	HumanReadableSI
+)
+
+// ParseOptions configures the tolerant number parsing done by
+// ParseWithOptions. The zero value reproduces Parse's strict, LC_ALL=C,
+// non-human-readable behavior.
+type ParseOptions struct {
+	Locale        NumberLocale
+	HumanReadable HumanReadableMode
+}
+
+// numberTokenPattern matches a number as rsync may print it under any
+// locale or --human-readable setting: digits, "." and "," in either role,
+// the NBSP/thin-space/narrow-no-break-space group separators glibc emits,
+// and an optional trailing do_big_num unit suffix.
+const numberTokenPattern = `[0-9][0-9.,\x{00A0}\x{2009}\x{202F}]*[KkMmGgTt]?`
+
+var numberTokenRe = regexp.MustCompile(`^([0-9.,\x{00A0}\x{2009}\x{202F}]*[0-9])([KkMmGgTt])?$`)
+
+// tolerant rewrites a strict, LC_ALL=C regexp (as used by parseLine) into
+// one that also matches localized and --human-readable numbers, by
+// replacing its number sub-patterns with numberTokenPattern.
+func tolerant(re *regexp.Regexp) *regexp.Regexp {
+	pattern := re.String()
+	for _, strict := range []string{`[0-9,]+`, `[0-9,.]+`, `[0-9.]+`} {
+		pattern = strings.ReplaceAll(pattern, strict, numberTokenPattern)
+	}
+	return regexp.MustCompile(pattern)
+}
+
+var (
+	statsTransferTolerantRe = tolerant(statsTransferRe)
+	statsSizeTolerantRe     = tolerant(statsSizeRe)
+
+	numFilesTolerantRe                   = tolerant(numFilesRe)
+	numCreatedFilesTolerantRe            = tolerant(numCreatedFilesRe)
+	numDeletedFilesTolerantRe            = tolerant(numDeletedFilesRe)
+	numRegularFilesTransferredTolerantRe = tolerant(numRegularFilesTransferredRe)
+	numFilesTransferredTolerantRe        = tolerant(numFilesTransferredRe)
+
+	totalFileSizeTolerantRe            = tolerant(totalFileSizeRe)
+	totalTransferredFileSizeTolerantRe = tolerant(totalTransferredFileSizeRe)
+
+	literalDataTolerantRe = tolerant(literalDataRe)
+	matchedDataTolerantRe = tolerant(matchedDataRe)
+
+	fileListSizeTolerantRe           = tolerant(fileListSizeRe)
+	fileListGenerationTimeTolerantRe = tolerant(fileListGenerationTimeRe)
+	fileListTransferTimeTolerantRe   = tolerant(fileListTransferTimeRe)
+
+	totalBytesSentTolerantRe     = tolerant(totalBytesSentRe)
+	totalBytesReceivedTolerantRe = tolerant(totalBytesReceivedRe)
+)
+
+// parseStatsFloat parses s, a number as rsync printed it under opts, into a
+// float64.
+func parseStatsFloat(s string, opts ParseOptions) (float64, error) {
+	matches := numberTokenRe.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, fmt.Errorf("rsyncparse: %q does not look like a number", s)
+	}
+	digits, suffix := matches[1], matches[2]
+
+	digits = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', ' ', ' ':
+			return -1
+		}
+		return r
+	}, digits)
+
+	switch opts.Locale {
+	case LocaleDotComma:
+		digits = strings.ReplaceAll(digits, ".", "")
+		digits = strings.ReplaceAll(digits, ",", ".")
+	case LocaleSpaceComma:
+		digits = strings.ReplaceAll(digits, ",", ".")
+	default: // LocaleC
+		digits = strings.ReplaceAll(digits, ",", "")
+	}
+
+	v, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if suffix != "" {
+		mult, ok := humanReadableMultiplier(suffix[0], opts.HumanReadable)
+		if !ok {
+			return 0, fmt.Errorf("rsyncparse: %q has a unit suffix but ParseOptions.HumanReadable is off", s)
+		}
+		v *= mult
+	}
+	return v, nil
+}
+
+// parseStatsInt parses s the same way as parseStatsFloat, rounding to the
+// nearest integer (human-readable numbers are themselves already rounded by
+// rsync, so this only matters for the multiplication by the unit suffix).
+func parseStatsInt(s string, opts ParseOptions) (int64, error) {
+	v, err := parseStatsFloat(s, opts)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(v)), nil
+}
+
+func humanReadableMultiplier(suffix byte, mode HumanReadableMode) (float64, bool) {
+	if mode == HumanReadableOff {
+		return 0, false
+	}
+	base := 1024.0
+	if mode == HumanReadableSI {
+		base = 1000.0
+	}
+	switch suffix {
+	case 'K', 'k':
+		return base, true
+	case 'M', 'm':
+		return base * base, true
+	case 'G', 'g':
+		return base * base * base, true
+	case 'T', 't':
+		return base * base * base * base, true
+	}
+	return 0, false
+}
+
+// SniffHumanReadable inspects a "sent ... bytes/sec" summary line (as also
+// matched by Parse) and reports whether its numbers carry a do_big_num unit
+// suffix, i.e. whether rsync was started with -h or -hh. It cannot tell -h
+// apart from -hh, since both print the same K/M/G/T suffixes and only the
+// underlying divisor differs; callers who know which flag was used should
+// set ParseOptions.HumanReadable directly instead of relying on this sniff.
+func SniffHumanReadable(line string) bool {
+	matches := statsTransferTolerantRe.FindStringSubmatch(line)
+	if matches == nil {
+		return false
+	}
+	for _, m := range matches[1:] {
+		if tok := numberTokenRe.FindStringSubmatch(m); tok != nil && tok[2] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWithOptions behaves like Parse, except that numbers are decoded
+// according to opts instead of assuming LC_ALL=C and no --human-readable.
+// Use this when rsync's output may come from a locale-sensitive cron job or
+// wrapper script you do not control.
+func ParseWithOptions(r io.Reader, opts ParseOptions) (*Stats, error) {
+	p := &Stats{}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		if err := p.parseLineWithOptions(scan.Text(), opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := scan.Err(); err != nil {
+		if err == io.EOF {
+			return p, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseLineWithOptions is parseLine's tolerant counterpart: same line
+// shapes, decoded via opts instead of the strict LC_ALL=C assumption.
+func (p *Stats) parseLineWithOptions(line string, opts ParseOptions) error {
+	switch {
+	case strings.HasPrefix(line, "sent "):
+		matches := statsTransferTolerantRe.FindStringSubmatch(line)
+		if len(matches) == 0 {
+			return fmt.Errorf("could not parse rsync 'sent' line %q", line)
+		}
+		p.Found = true
+		var err error
+		p.TotalWritten, err = parseStatsInt(matches[1], opts)
+		if err != nil {
+			return err
+		}
+		p.TotalRead, err = parseStatsInt(matches[2], opts)
+		if err != nil {
+			return err
+		}
+		p.BytesPerSec, err = parseStatsFloat(matches[3], opts)
+		if err != nil {
+			return err
+		}
+
+	case strings.HasPrefix(line, "total size is "):
+		matches := statsSizeTolerantRe.FindStringSubmatch(line)
+		if len(matches) == 0 {
+			return fmt.Errorf("could not parse rsync 'total size is' line %q", line)
+		}
+		p.Found = true
+		var err error
+		p.TotalSize, err = parseStatsInt(matches[1], opts)
+		if err != nil {
+			return err
+		}
+
+	case strings.HasPrefix(line, "Number of files: "):
+		if matches := numFilesTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumFiles, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Number of created files: "):
+		if matches := numCreatedFilesTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumCreatedFiles, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Number of deleted files: "):
+		if matches := numDeletedFilesTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumDeletedFiles, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Number of regular files transferred: "):
+		if matches := numRegularFilesTransferredTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumRegularFilesTransferred, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Number of files transferred: "):
+		if matches := numFilesTransferredTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumRegularFilesTransferred, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Total file size: "):
+		if matches := totalFileSizeTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalFileSize, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Total transferred file size: "):
+		if matches := totalTransferredFileSizeTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalTransferredFileSize, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Literal data: "):
+		if matches := literalDataTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.LiteralData, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Matched data: "):
+		if matches := matchedDataTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.MatchedData, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "File list size: "):
+		if matches := fileListSizeTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.FileListSize, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "File list generation time: "):
+		if matches := fileListGenerationTimeTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.FileListGenerationTime, err = parseStatsFloat(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "File list transfer time: "):
+		if matches := fileListTransferTimeTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.FileListTransferTime, err = parseStatsFloat(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Total bytes sent: "):
+		if matches := totalBytesSentTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalBytesSent, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Total bytes received: "):
+		if matches := totalBytesReceivedTolerantRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalBytesReceived, err = parseStatsInt(matches[1], opts)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		// --itemize-changes lines are locale- and
+		// --human-readable-independent, so the strict parser already
+		// handles them; fall back to it for everything else.
+		return p.parseLine(line)
+	}
+	return nil
+}
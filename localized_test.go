@@ -0,0 +1,78 @@
+package rsyncparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWithOptionsLocales(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input string
+		opts  ParseOptions
+		want  Stats
+	}{
+		{
+			name: "LC_ALL=C, unchanged from Parse",
+			input: `sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec
+total size is 1,188,046  speedup is 738.83
+`,
+			opts: ParseOptions{},
+			want: Stats{Found: true, TotalWritten: 1590, TotalRead: 18, BytesPerSec: 3216.00, TotalSize: 1188046},
+		},
+		{
+			name: "de_DE style, dot thousands comma decimal",
+			input: "sent 1.590 bytes  received 18 bytes  3.216,00 bytes/sec\n" +
+				"total size is 1.188.046  speedup is 738,83\n",
+			opts: ParseOptions{Locale: LocaleDotComma},
+			want: Stats{Found: true, TotalWritten: 1590, TotalRead: 18, BytesPerSec: 3216.00, TotalSize: 1188046},
+		},
+		{
+			name: "fr_FR style, NBSP thousands comma decimal",
+			input: "sent 1 590 bytes  received 18 bytes  3 216,00 bytes/sec\n" +
+				"total size is 1 188 046  speedup is 738,83\n",
+			opts: ParseOptions{Locale: LocaleSpaceComma},
+			want: Stats{Found: true, TotalWritten: 1590, TotalRead: 18, BytesPerSec: 3216.00, TotalSize: 1188046},
+		},
+		{
+			name: "-h binary human-readable",
+			input: `sent 1.55K bytes  received 18 bytes  3.14K bytes/sec
+total size is 1.13M  speedup is 738.83
+`,
+			opts: ParseOptions{HumanReadable: HumanReadableBinary},
+			want: Stats{Found: true, TotalWritten: 1587, TotalRead: 18, BytesPerSec: 3215.36, TotalSize: 1184891},
+		},
+		{
+			name: "-hh SI human-readable",
+			input: `sent 1.55K bytes  received 18 bytes  3.14K bytes/sec
+total size is 1.13M  speedup is 738.83
+`,
+			opts: ParseOptions{HumanReadable: HumanReadableSI},
+			want: Stats{Found: true, TotalWritten: 1550, TotalRead: 18, BytesPerSec: 3140, TotalSize: 1130000},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWithOptions(strings.NewReader(tt.input), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseWithOptions() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffHumanReadable(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		want bool
+	}{
+		{"sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec", false},
+		{"sent 1.55K bytes  received 18 bytes  3.14K bytes/sec", true},
+	} {
+		if got := SniffHumanReadable(tt.line); got != tt.want {
+			t.Errorf("SniffHumanReadable(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
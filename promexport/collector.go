@@ -0,0 +1,128 @@
+//go:build promexport
+
+package promexport
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stapelberg/rsyncparse"
+)
+
+// Collector is a prometheus.Collector whose gauges and counters mirror the
+// fields of a *rsyncparse.Stats.
+type Collector struct {
+	stats *rsyncparse.Stats
+
+	bytesSent                  *prometheus.Desc
+	bytesReceived              *prometheus.Desc
+	bytesPerSec                *prometheus.Desc
+	totalSize                  *prometheus.Desc
+	speedup                    *prometheus.Desc
+	numFiles                   *prometheus.Desc
+	numCreatedFiles            *prometheus.Desc
+	numDeletedFiles            *prometheus.Desc
+	numRegularFilesTransferred *prometheus.Desc
+	totalFileSize              *prometheus.Desc
+	totalTransferredFileSize   *prometheus.Desc
+	literalData                *prometheus.Desc
+	matchedData                *prometheus.Desc
+	fileListSize               *prometheus.Desc
+	fileListGenerationTime     *prometheus.Desc
+	fileListTransferTime       *prometheus.Desc
+	filesCreated               *prometheus.Desc
+	filesDeleted               *prometheus.Desc
+	filesUpdated               *prometheus.Desc
+	symlinksChanged            *prometheus.Desc
+	dirsChanged                *prometheus.Desc
+}
+
+// NewCollector returns a Collector that reads from stats whenever Prometheus
+// scrapes or pushes it. stats is typically the *rsyncparse.Stats returned by
+// Parse, ParseWithOptions or ParseStream once the transfer has completed.
+func NewCollector(stats *rsyncparse.Stats) *Collector {
+	return &Collector{
+		stats: stats,
+
+		bytesSent:     prometheus.NewDesc("rsync_bytes_sent_total", "Total bytes sent by rsync.", nil, nil),
+		bytesReceived: prometheus.NewDesc("rsync_bytes_received_total", "Total bytes received by rsync.", nil, nil),
+		bytesPerSec:   prometheus.NewDesc("rsync_bytes_per_second", "Transfer rate reported by rsync.", nil, nil),
+		totalSize:     prometheus.NewDesc("rsync_total_size_bytes", "Total size of the files in the transfer.", nil, nil),
+		speedup:       prometheus.NewDesc("rsync_speedup_ratio", "Speed-up of using rsync over copying the data as-is.", nil, nil),
+
+		numFiles:                   prometheus.NewDesc("rsync_files_total", "Number of files in the transfer.", nil, nil),
+		numCreatedFiles:            prometheus.NewDesc("rsync_files_created_total", "Number of created files, from --stats output.", nil, nil),
+		numDeletedFiles:            prometheus.NewDesc("rsync_files_deleted_total", "Number of deleted files, from --stats output.", nil, nil),
+		numRegularFilesTransferred: prometheus.NewDesc("rsync_regular_files_transferred_total", "Number of regular files transferred.", nil, nil),
+
+		totalFileSize:            prometheus.NewDesc("rsync_file_size_bytes_total", "Total size of all files in the transfer.", nil, nil),
+		totalTransferredFileSize: prometheus.NewDesc("rsync_transferred_file_size_bytes_total", "Total size of the files actually transferred.", nil, nil),
+		literalData:              prometheus.NewDesc("rsync_literal_data_bytes_total", "Literal (non-matched) data sent.", nil, nil),
+		matchedData:              prometheus.NewDesc("rsync_matched_data_bytes_total", "Data matched against the destination and not re-sent.", nil, nil),
+
+		fileListSize:           prometheus.NewDesc("rsync_file_list_size_bytes", "Size of the transferred file list.", nil, nil),
+		fileListGenerationTime: prometheus.NewDesc("rsync_file_list_generation_seconds", "Time rsync spent generating the file list.", nil, nil),
+		fileListTransferTime:   prometheus.NewDesc("rsync_file_list_transfer_seconds", "Time rsync spent transferring the file list.", nil, nil),
+
+		filesCreated:    prometheus.NewDesc("rsync_itemized_files_created_total", "Files created, from --itemize-changes output.", nil, nil),
+		filesDeleted:    prometheus.NewDesc("rsync_itemized_files_deleted_total", "Files deleted, from --itemize-changes output.", nil, nil),
+		filesUpdated:    prometheus.NewDesc("rsync_itemized_files_updated_total", "Files updated, from --itemize-changes output.", nil, nil),
+		symlinksChanged: prometheus.NewDesc("rsync_itemized_symlinks_changed_total", "Symlinks changed, from --itemize-changes output.", nil, nil),
+		dirsChanged:     prometheus.NewDesc("rsync_itemized_dirs_changed_total", "Directories changed, from --itemize-changes output.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.bytesSent
+	ch <- c.bytesReceived
+	ch <- c.bytesPerSec
+	ch <- c.totalSize
+	ch <- c.speedup
+	ch <- c.numFiles
+	ch <- c.numCreatedFiles
+	ch <- c.numDeletedFiles
+	ch <- c.numRegularFilesTransferred
+	ch <- c.totalFileSize
+	ch <- c.totalTransferredFileSize
+	ch <- c.literalData
+	ch <- c.matchedData
+	ch <- c.fileListSize
+	ch <- c.fileListGenerationTime
+	ch <- c.fileListTransferTime
+	ch <- c.filesCreated
+	ch <- c.filesDeleted
+	ch <- c.filesUpdated
+	ch <- c.symlinksChanged
+	ch <- c.dirsChanged
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats
+	ch <- prometheus.MustNewConstMetric(c.bytesSent, prometheus.CounterValue, float64(s.TotalWritten))
+	ch <- prometheus.MustNewConstMetric(c.bytesReceived, prometheus.CounterValue, float64(s.TotalRead))
+	ch <- prometheus.MustNewConstMetric(c.bytesPerSec, prometheus.GaugeValue, s.BytesPerSec)
+	ch <- prometheus.MustNewConstMetric(c.totalSize, prometheus.GaugeValue, float64(s.TotalSize))
+	if s.TotalWritten+s.TotalRead > 0 {
+		ch <- prometheus.MustNewConstMetric(c.speedup, prometheus.GaugeValue, s.Speedup())
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.numFiles, prometheus.GaugeValue, float64(s.NumFiles))
+	ch <- prometheus.MustNewConstMetric(c.numCreatedFiles, prometheus.GaugeValue, float64(s.NumCreatedFiles))
+	ch <- prometheus.MustNewConstMetric(c.numDeletedFiles, prometheus.GaugeValue, float64(s.NumDeletedFiles))
+	ch <- prometheus.MustNewConstMetric(c.numRegularFilesTransferred, prometheus.GaugeValue, float64(s.NumRegularFilesTransferred))
+
+	ch <- prometheus.MustNewConstMetric(c.totalFileSize, prometheus.GaugeValue, float64(s.TotalFileSize))
+	ch <- prometheus.MustNewConstMetric(c.totalTransferredFileSize, prometheus.GaugeValue, float64(s.TotalTransferredFileSize))
+	ch <- prometheus.MustNewConstMetric(c.literalData, prometheus.CounterValue, float64(s.LiteralData))
+	ch <- prometheus.MustNewConstMetric(c.matchedData, prometheus.CounterValue, float64(s.MatchedData))
+
+	ch <- prometheus.MustNewConstMetric(c.fileListSize, prometheus.GaugeValue, float64(s.FileListSize))
+	ch <- prometheus.MustNewConstMetric(c.fileListGenerationTime, prometheus.GaugeValue, s.FileListGenerationTime)
+	ch <- prometheus.MustNewConstMetric(c.fileListTransferTime, prometheus.GaugeValue, s.FileListTransferTime)
+
+	ch <- prometheus.MustNewConstMetric(c.filesCreated, prometheus.CounterValue, float64(s.FilesCreated))
+	ch <- prometheus.MustNewConstMetric(c.filesDeleted, prometheus.CounterValue, float64(s.FilesDeleted))
+	ch <- prometheus.MustNewConstMetric(c.filesUpdated, prometheus.CounterValue, float64(s.FilesUpdated))
+	ch <- prometheus.MustNewConstMetric(c.symlinksChanged, prometheus.CounterValue, float64(s.SymlinksChanged))
+	ch <- prometheus.MustNewConstMetric(c.dirsChanged, prometheus.CounterValue, float64(s.DirsChanged))
+}
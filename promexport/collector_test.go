@@ -0,0 +1,57 @@
+//go:build promexport
+
+package promexport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stapelberg/rsyncparse"
+)
+
+func TestCollector(t *testing.T) {
+	stats := &rsyncparse.Stats{
+		TotalWritten:               1590,
+		TotalRead:                  18,
+		BytesPerSec:                3216.00,
+		TotalSize:                  1188046,
+		NumFiles:                   5,
+		NumRegularFilesTransferred: 2,
+		FilesCreated:               1,
+		FilesUpdated:               2,
+		SymlinksChanged:            1,
+		DirsChanged:                1,
+		FilesDeleted:               1,
+	}
+	c := NewCollector(stats)
+
+	want := `
+# HELP rsync_bytes_sent_total Total bytes sent by rsync.
+# TYPE rsync_bytes_sent_total counter
+rsync_bytes_sent_total 1590
+# HELP rsync_speedup_ratio Speed-up of using rsync over copying the data as-is.
+# TYPE rsync_speedup_ratio gauge
+rsync_speedup_ratio 738.8345771144278
+# HELP rsync_itemized_files_updated_total Files updated, from --itemize-changes output.
+# TYPE rsync_itemized_files_updated_total counter
+rsync_itemized_files_updated_total 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"rsync_bytes_sent_total", "rsync_speedup_ratio", "rsync_itemized_files_updated_total"); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectorOmitsSpeedupWhenNothingTransferred(t *testing.T) {
+	c := NewCollector(&rsyncparse.Stats{})
+
+	want := `
+# HELP rsync_files_total Number of files in the transfer.
+# TYPE rsync_files_total gauge
+rsync_files_total 0
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "rsync_files_total", "rsync_speedup_ratio"); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
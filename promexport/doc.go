@@ -0,0 +1,15 @@
+// Package promexport provides optional Prometheus integration for package
+// rsyncparse: a prometheus.Collector exposing a Stats value as metrics, a
+// Push helper for the Prometheus pushgateway, and a RunAndExport helper that
+// runs rsync and exports its progress continuously while it is running.
+//
+// It supersedes the separate https://github.com/stapelberg/rsyncprom
+// project; prefer this package for new code.
+//
+// This package depends on github.com/prometheus/client_golang, which the
+// core rsyncparse package does not. It is gated behind the "promexport"
+// build tag so that building rsyncparse itself never requires that
+// dependency:
+//
+//	go build -tags promexport ./...
+package promexport
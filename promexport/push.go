@@ -0,0 +1,20 @@
+//go:build promexport
+
+package promexport
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/stapelberg/rsyncparse"
+)
+
+// Push pushes the metrics mirroring stats to the Prometheus pushgateway
+// reachable at pushgatewayURL, grouped under the given job and instance
+// labels.
+func Push(ctx context.Context, pushgatewayURL, job, instance string, stats *rsyncparse.Stats) error {
+	return push.New(pushgatewayURL, job).
+		Grouping("instance", instance).
+		Collector(NewCollector(stats)).
+		PushContext(ctx)
+}
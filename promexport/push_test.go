@@ -0,0 +1,50 @@
+//go:build promexport
+
+package promexport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stapelberg/rsyncparse"
+)
+
+func TestPush(t *testing.T) {
+	var gotPath string
+	var gotSent float64
+	var foundSent bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		// The pusher encodes metric families in the format named by the
+		// Content-Type header (delimited protobuf by default).
+		dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			var mf dto.MetricFamily
+			if err := dec.Decode(&mf); err != nil {
+				break
+			}
+			if mf.GetName() == "rsync_bytes_sent_total" && len(mf.GetMetric()) == 1 {
+				gotSent, foundSent = mf.GetMetric()[0].GetCounter().GetValue(), true
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	stats := &rsyncparse.Stats{TotalWritten: 1590, TotalRead: 18}
+	if err := Push(context.Background(), srv.URL, "rsync-backup", "myhost", stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(gotPath, "/job/rsync-backup/") || !strings.HasSuffix(gotPath, "/instance/myhost") {
+		t.Errorf("pushed to path %q, want it to contain job %q and instance %q", gotPath, "rsync-backup", "myhost")
+	}
+	if !foundSent || gotSent != 1590 {
+		t.Errorf("pushed rsync_bytes_sent_total = %v (found: %v), want 1590", gotSent, foundSent)
+	}
+}
@@ -0,0 +1,110 @@
+//go:build promexport
+
+package promexport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/stapelberg/rsyncparse"
+)
+
+// RunAndExportOptions configures RunAndExport.
+type RunAndExportOptions struct {
+	// PushgatewayURL, Job and Instance identify where, and under which
+	// labels, progress and the final Stats are pushed.
+	PushgatewayURL string
+	Job            string
+	Instance       string
+
+	// MinPushInterval rate-limits how often progress updates are pushed
+	// to the pushgateway, regardless of how often rsync redraws its
+	// progress line. It defaults to 5 seconds if zero.
+	MinPushInterval time.Duration
+}
+
+// RunAndExport starts cmd, which must be an rsync invocation printing
+// --info=progress2 (or --progress) output in addition to --stats on
+// stdout, and pushes progress to a Prometheus pushgateway as the transfer
+// runs. It returns the final Stats once cmd exits, having pushed those too.
+//
+// Progress push failures are logged but do not abort the transfer; only the
+// final push's error is returned to the caller.
+func RunAndExport(ctx context.Context, cmd *exec.Cmd, opts RunAndExportOptions) (*rsyncparse.Stats, error) {
+	minInterval := opts.MinPushInterval
+	if minInterval == 0 {
+		minInterval = 5 * time.Second
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("rsync stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting rsync: %w", err)
+	}
+
+	var lastPush time.Time
+	stats, parseErr := rsyncparse.ParseStream(stdout, func(ev rsyncparse.Event) {
+		now := time.Now()
+		if now.Sub(lastPush) < minInterval {
+			return
+		}
+		lastPush = now
+		pusher := push.New(opts.PushgatewayURL, opts.Job).
+			Grouping("instance", opts.Instance).
+			Collector(newProgressCollector(ev))
+		if err := pusher.PushContext(ctx); err != nil {
+			log.Printf("promexport: pushing progress: %v", err)
+		}
+	})
+	if waitErr := cmd.Wait(); waitErr != nil {
+		return nil, fmt.Errorf("rsync: %w", waitErr)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("parsing rsync output: %w", parseErr)
+	}
+
+	if err := Push(ctx, opts.PushgatewayURL, opts.Job, opts.Instance, stats); err != nil {
+		return stats, fmt.Errorf("pushing final stats: %w", err)
+	}
+	return stats, nil
+}
+
+// progressCollector is a prometheus.Collector exposing only the gauges that
+// a single progress Event actually carries, so that pushing it during a
+// transfer does not zero out the --stats/--itemize-changes metrics that
+// Collector exposes (those are only known once the transfer completes).
+type progressCollector struct {
+	ev rsyncparse.Event
+
+	bytesSoFar  *prometheus.Desc
+	percent     *prometheus.Desc
+	bytesPerSec *prometheus.Desc
+}
+
+func newProgressCollector(ev rsyncparse.Event) *progressCollector {
+	return &progressCollector{
+		ev:          ev,
+		bytesSoFar:  prometheus.NewDesc("rsync_progress_bytes_so_far", "Bytes transferred so far, from --info=progress2/--progress output.", nil, nil),
+		percent:     prometheus.NewDesc("rsync_progress_percent", "Percent complete, from --info=progress2/--progress output.", nil, nil),
+		bytesPerSec: prometheus.NewDesc("rsync_progress_bytes_per_second", "Instantaneous transfer rate, from --info=progress2/--progress output.", nil, nil),
+	}
+}
+
+func (c *progressCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.bytesSoFar
+	ch <- c.percent
+	ch <- c.bytesPerSec
+}
+
+func (c *progressCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.bytesSoFar, prometheus.GaugeValue, float64(c.ev.BytesSoFar))
+	ch <- prometheus.MustNewConstMetric(c.percent, prometheus.GaugeValue, float64(c.ev.Percent))
+	ch <- prometheus.MustNewConstMetric(c.bytesPerSec, prometheus.GaugeValue, c.ev.BytesPerSec)
+}
@@ -0,0 +1,56 @@
+//go:build promexport
+
+package promexport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// progressScript prints classic --progress-style output for a single file:
+// two "\r"-redrawn intermediate lines (no push should beat MinPushInterval
+// for both of them), followed by the completion line and the usual
+// --stats summary.
+const progressScript = `
+printf '        32,768   7%%    3.20MB/s    0:00:02\r'
+printf '       524,288  50%%    8.01MB/s    0:00:01\r'
+printf '     1,048,576 100%%   10.45MB/s    0:00:00 (xfr#1, to-chk=0/1)\n'
+printf '\n'
+printf 'sent 1,100 bytes  received 35 bytes  756.67 bytes/sec\n'
+printf 'total size is 1,048,576  speedup is 923.52\n'
+`
+
+func TestRunAndExportRateLimitsProgressPushes(t *testing.T) {
+	var pushes int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pushes, 1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	stats, err := RunAndExport(context.Background(), exec.Command("sh", "-c", progressScript), RunAndExportOptions{
+		PushgatewayURL: srv.URL,
+		Job:            "test",
+		Instance:       "inst",
+		// Longer than the whole script's runtime, so only the first of the
+		// three progress events should be pushed.
+		MinPushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Found {
+		t.Fatal("stats.Found = false, want true")
+	}
+
+	// One progress push (the first event; the rest are rate-limited away)
+	// plus one final push of the completed Stats.
+	if got, want := atomic.LoadInt32(&pushes), int32(2); got != want {
+		t.Errorf("pushgateway received %d pushes, want %d", got, want)
+	}
+}
@@ -9,11 +9,10 @@
 // Start rsync with --verbose (-v) or --stats to enable printing transfer
 // totals.
 //
-// Do not use the --human-readable (-h) flag in your rsync invocation, otherwise
-// rsyncprom cannot parse the output!
-//
-// Run rsync in the C.UTF-8 locale to prevent rsync from localizing decimal
-// separators and fractional points in big numbers.
+// Parse requires rsync to be run in the C.UTF-8 locale without
+// --human-readable (-h); otherwise it cannot parse the output. If you do not
+// control the locale or flags rsync is invoked with, use ParseWithOptions
+// instead, which tolerates localized decimal separators and -h/-hh output.
 package rsyncparse
 
 import (
@@ -33,67 +32,283 @@ type Stats struct {
 	TotalRead    int64
 	BytesPerSec  float64
 	TotalSize    int64
+
+	// The following fields are only populated when rsync was started with
+	// --stats; --verbose alone prints only the two summary lines above.
+
+	NumFiles                   int64
+	NumCreatedFiles            int64
+	NumDeletedFiles            int64
+	NumRegularFilesTransferred int64
+
+	TotalFileSize            int64
+	TotalTransferredFileSize int64
+
+	LiteralData int64
+	MatchedData int64
+
+	FileListSize int64
+	// FileListGenerationTime and FileListTransferTime are given in seconds,
+	// as printed by rsync.
+	FileListGenerationTime float64
+	FileListTransferTime   float64
+
+	TotalBytesSent     int64
+	TotalBytesReceived int64
+
+	// The following fields are only populated when rsync was started with
+	// --itemize-changes (-i), and count the items seen in that output.
+	FilesCreated    int64
+	FilesDeleted    int64
+	FilesUpdated    int64
+	SymlinksChanged int64
+	DirsChanged     int64
 }
 
 // Speedup calculates the speed-up of using rsync over copying the data as-is.
 func (p *Stats) Speedup() float64 {
-	return float64(p.TotalSize / (p.TotalWritten + p.TotalRead))
+	return float64(p.TotalSize) / float64(p.TotalWritten+p.TotalRead)
 }
 
 var (
 	statsTransferRe = regexp.MustCompile(`^sent ([0-9,]+) bytes  received ([0-9,]+) bytes  ([0-9,.]+) bytes/sec$`)
 
 	statsSizeRe = regexp.MustCompile(`^total size is ([0-9,]+)  speedup is ([0-9,.]+)$`)
+
+	// The following regexps match the lines printed as part of the
+	// --stats block. Newer rsync versions append a parenthesized
+	// breakdown (e.g. "(reg: 1,000, dir: 200)") to some of these lines;
+	// that breakdown is intentionally not captured and is discarded.
+	numFilesRe                   = regexp.MustCompile(`^Number of files: ([0-9,]+)(?: \(.*\))?$`)
+	numCreatedFilesRe            = regexp.MustCompile(`^Number of created files: ([0-9,]+)(?: \(.*\))?$`)
+	numDeletedFilesRe            = regexp.MustCompile(`^Number of deleted files: ([0-9,]+)(?: \(.*\))?$`)
+	numRegularFilesTransferredRe = regexp.MustCompile(`^Number of regular files transferred: ([0-9,]+)$`)
+	// numFilesTransferredRe matches the wording used by rsync 2.6 through
+	// 3.0; rsync 3.1 renamed this line to "Number of regular files
+	// transferred", presumably to disambiguate it from "Number of files".
+	numFilesTransferredRe = regexp.MustCompile(`^Number of files transferred: ([0-9,]+)$`)
+
+	totalFileSizeRe            = regexp.MustCompile(`^Total file size: ([0-9,]+) bytes$`)
+	totalTransferredFileSizeRe = regexp.MustCompile(`^Total transferred file size: ([0-9,]+) bytes(?: \(.*\))?$`)
+
+	literalDataRe = regexp.MustCompile(`^Literal data: ([0-9,]+) bytes$`)
+	matchedDataRe = regexp.MustCompile(`^Matched data: ([0-9,]+) bytes$`)
+
+	fileListSizeRe           = regexp.MustCompile(`^File list size: ([0-9,]+)$`)
+	fileListGenerationTimeRe = regexp.MustCompile(`^File list generation time: ([0-9.]+) seconds$`)
+	fileListTransferTimeRe   = regexp.MustCompile(`^File list transfer time: ([0-9.]+) seconds$`)
+
+	totalBytesSentRe     = regexp.MustCompile(`^Total bytes sent: ([0-9,]+)$`)
+	totalBytesReceivedRe = regexp.MustCompile(`^Total bytes received: ([0-9,]+)$`)
 )
 
+// parseCommaInt parses a decimal integer from which rsync's "," thousands
+// separators have not yet been stripped.
+func parseCommaInt(s string) (int64, error) {
+	return strconv.ParseInt(strings.ReplaceAll(s, ",", ""), 10, 64)
+}
+
+// parseCommaFloat parses a decimal float from which rsync's "," thousands
+// separators have not yet been stripped.
+func parseCommaFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+}
+
 // Parse reads from the specified io.Reader and scans individual lines. rsync
 // transfer totals are extracted when found, and returned in the Stats struct.
 func Parse(r io.Reader) (*Stats, error) {
 	p := &Stats{}
 	scan := bufio.NewScanner(r)
 	for scan.Scan() {
-		line := scan.Text()
-		// log.Printf("rsync output line: %q", line)
-		if strings.HasPrefix(line, "sent ") {
-			// e.g.:
-			// sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec
-			// total size is 1,188,046  speedup is 738.83
-			matches := statsTransferRe.FindStringSubmatch(line)
-			if len(matches) == 0 {
-				return nil, fmt.Errorf("could not parse rsync 'sent' line; try starting rsync with LC_ALL=C.UTF-8")
+		if err := p.parseLine(scan.Text()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scan.Err(); err != nil {
+		if err == io.EOF {
+			return p, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseLine recognizes a single line of rsync's --verbose/--stats summary
+// output and, if recognized, updates p accordingly. Lines that are not
+// recognized (e.g. progress output handled separately by ParseStream) are
+// ignored.
+func (p *Stats) parseLine(line string) error {
+	// log.Printf("rsync output line: %q", line)
+	switch {
+	case strings.HasPrefix(line, "sent "):
+		// e.g.:
+		// sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec
+		// total size is 1,188,046  speedup is 738.83
+		matches := statsTransferRe.FindStringSubmatch(line)
+		if len(matches) == 0 {
+			return fmt.Errorf("could not parse rsync 'sent' line; try starting rsync with LC_ALL=C.UTF-8")
+		}
+
+		p.Found = true
+		// parse rsync do_big_num(int64 num) output
+		// parse 1[,.]192[,.]097 bytes
+		var err error
+		p.TotalWritten, err = parseCommaInt(matches[1])
+		if err != nil {
+			return err
+		}
+		p.TotalRead, err = parseCommaInt(matches[2])
+		if err != nil {
+			return err
+		}
+		p.BytesPerSec, err = parseCommaFloat(matches[3])
+		if err != nil {
+			return err
+		}
+
+	case strings.HasPrefix(line, "total size is "):
+		matches := statsSizeRe.FindStringSubmatch(line)
+		p.Found = true
+		var err error
+		p.TotalSize, err = parseCommaInt(matches[1])
+		if err != nil {
+			return err
+		}
+
+	case strings.HasPrefix(line, "Number of files: "):
+		if matches := numFilesRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumFiles, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
 			}
+		}
 
-			p.Found = true
-			// parse rsync do_big_num(int64 num) output
-			// parse 1[,.]192[,.]097 bytes
+	case strings.HasPrefix(line, "Number of created files: "):
+		if matches := numCreatedFilesRe.FindStringSubmatch(line); len(matches) != 0 {
 			var err error
-			p.TotalWritten, err = strconv.ParseInt(strings.ReplaceAll(matches[1], ",", ""), 0, 64)
+			p.NumCreatedFiles, err = parseCommaInt(matches[1])
 			if err != nil {
-				return nil, err
+				return err
 			}
-			p.TotalRead, err = strconv.ParseInt(strings.ReplaceAll(matches[2], ",", ""), 0, 64)
+		}
+
+	case strings.HasPrefix(line, "Number of deleted files: "):
+		if matches := numDeletedFilesRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumDeletedFiles, err = parseCommaInt(matches[1])
 			if err != nil {
-				return nil, err
+				return err
 			}
-			p.BytesPerSec, err = strconv.ParseFloat(strings.ReplaceAll(matches[3], ",", ""), 64)
+		}
+
+	case strings.HasPrefix(line, "Number of regular files transferred: "):
+		if matches := numRegularFilesTransferredRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.NumRegularFilesTransferred, err = parseCommaInt(matches[1])
 			if err != nil {
-				return nil, err
+				return err
 			}
-		} else if strings.HasPrefix(line, "total size is ") {
-			matches := statsSizeRe.FindStringSubmatch(line)
-			p.Found = true
+		}
+
+	case strings.HasPrefix(line, "Number of files transferred: "):
+		// rsync 2.6 through 3.0 wording; see numFilesTransferredRe.
+		if matches := numFilesTransferredRe.FindStringSubmatch(line); len(matches) != 0 {
 			var err error
-			p.TotalSize, err = strconv.ParseInt(strings.ReplaceAll(matches[1], ",", ""), 0, 64)
+			p.NumRegularFilesTransferred, err = parseCommaInt(matches[1])
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
-	}
-	if err := scan.Err(); err != nil {
-		if err == io.EOF {
-			return p, nil
+
+	case strings.HasPrefix(line, "Total file size: "):
+		if matches := totalFileSizeRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalFileSize, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Total transferred file size: "):
+		if matches := totalTransferredFileSizeRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalTransferredFileSize, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Literal data: "):
+		if matches := literalDataRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.LiteralData, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Matched data: "):
+		if matches := matchedDataRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.MatchedData, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "File list size: "):
+		if matches := fileListSizeRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.FileListSize, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "File list generation time: "):
+		if matches := fileListGenerationTimeRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.FileListGenerationTime, err = parseCommaFloat(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "File list transfer time: "):
+		if matches := fileListTransferTimeRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.FileListTransferTime, err = parseCommaFloat(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Total bytes sent: "):
+		if matches := totalBytesSentRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalBytesSent, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(line, "Total bytes received: "):
+		if matches := totalBytesReceivedRe.FindStringSubmatch(line); len(matches) != 0 {
+			var err error
+			p.TotalBytesReceived, err = parseCommaInt(matches[1])
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		// Not a recognized summary line; it might be a line of
+		// --itemize-changes (-i) output. Lines produced by a custom
+		// --out-format that don't match are silently ignored.
+		if ic, ok := ParseItemizeLine(line); ok {
+			p.applyItemChange(ic)
 		}
-		return nil, err
 	}
-	return p, nil
+	return nil
 }
@@ -0,0 +1,150 @@
+package rsyncparse
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseStats(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input string
+		want  Stats
+	}{
+		{
+			name: "rsync 2.6 --stats",
+			input: `Number of files: 5
+Number of files transferred: 2
+Total file size: 1188046 bytes
+Total transferred file size: 123456 bytes
+Literal data: 123456 bytes
+Matched data: 0 bytes
+File list size: 123
+Total bytes sent: 1590
+Total bytes received: 18
+
+sent 1590 bytes  received 18 bytes  3216.00 bytes/sec
+total size is 1188046  speedup is 738.83
+`,
+			want: Stats{
+				Found:                      true,
+				TotalWritten:               1590,
+				TotalRead:                  18,
+				BytesPerSec:                3216.00,
+				TotalSize:                  1188046,
+				NumFiles:                   5,
+				NumRegularFilesTransferred: 2,
+				TotalFileSize:              1188046,
+				TotalTransferredFileSize:   123456,
+				LiteralData:                123456,
+				FileListSize:               123,
+				TotalBytesSent:             1590,
+				TotalBytesReceived:         18,
+			},
+		},
+		{
+			name: "rsync 3.1 --stats",
+			input: `Number of files: 5 (reg: 4, dir: 1)
+Number of created files: 0
+Number of deleted files: 0
+Number of regular files transferred: 2
+Total file size: 1,188,046 bytes
+Total transferred file size: 123,456 bytes
+Literal data: 123,456 bytes
+Matched data: 0 bytes
+File list size: 123
+File list generation time: 0.001 seconds
+File list transfer time: 0.000 seconds
+Total bytes sent: 1,590
+Total bytes received: 18
+
+sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec
+total size is 1,188,046  speedup is 738.83
+`,
+			want: Stats{
+				Found:                      true,
+				TotalWritten:               1590,
+				TotalRead:                  18,
+				BytesPerSec:                3216.00,
+				TotalSize:                  1188046,
+				NumFiles:                   5,
+				NumRegularFilesTransferred: 2,
+				TotalFileSize:              1188046,
+				TotalTransferredFileSize:   123456,
+				LiteralData:                123456,
+				FileListSize:               123,
+				FileListGenerationTime:     0.001,
+				TotalBytesSent:             1590,
+				TotalBytesReceived:         18,
+			},
+		},
+		{
+			name: "rsync 3.2/3.3 --stats with created/deleted breakdown",
+			input: `Number of files: 5 (reg: 4, dir: 1)
+Number of created files: 1 (reg: 1)
+Number of deleted files: 0
+Number of regular files transferred: 2
+Total file size: 1,188,046 bytes
+Total transferred file size: 123,456 bytes (reg: 123,456)
+Literal data: 123,456 bytes
+Matched data: 0 bytes
+File list size: 123
+File list generation time: 0.001 seconds
+File list transfer time: 0.000 seconds
+Total bytes sent: 1,590
+Total bytes received: 18
+
+sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec
+total size is 1,188,046  speedup is 738.83
+`,
+			want: Stats{
+				Found:                      true,
+				TotalWritten:               1590,
+				TotalRead:                  18,
+				BytesPerSec:                3216.00,
+				TotalSize:                  1188046,
+				NumFiles:                   5,
+				NumCreatedFiles:            1,
+				NumRegularFilesTransferred: 2,
+				TotalFileSize:              1188046,
+				TotalTransferredFileSize:   123456,
+				LiteralData:                123456,
+				FileListSize:               123,
+				FileListGenerationTime:     0.001,
+				TotalBytesSent:             1590,
+				TotalBytesReceived:         18,
+			},
+		},
+		{
+			name: "verbose-only, no --stats block",
+			input: `sent 1,590 bytes  received 18 bytes  3,216.00 bytes/sec
+total size is 1,188,046  speedup is 738.83
+`,
+			want: Stats{
+				Found:        true,
+				TotalWritten: 1590,
+				TotalRead:    18,
+				BytesPerSec:  3216.00,
+				TotalSize:    1188046,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpeedup(t *testing.T) {
+	p := &Stats{TotalSize: 1188046, TotalWritten: 1590, TotalRead: 18}
+	if got, want := p.Speedup(), 738.8345771144278; math.Abs(got-want) > 1e-6 {
+		t.Errorf("Speedup() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package rsyncparse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"time"
+)
+
+// Event is a single progress update parsed from rsync's --info=progress2 or
+// --progress output, e.g.:
+//
+//	1,234,567  42%   12.34MB/s    0:01:23 (xfr#17, to-chk=203/455)
+type Event struct {
+	// BytesSoFar is the number of bytes transferred so far (for the whole
+	// transfer when using --info=progress2, or for the current file when
+	// using --progress).
+	BytesSoFar int64
+	Percent    int
+	// BytesPerSec is the instantaneous transfer rate.
+	BytesPerSec float64
+	ETA         time.Duration
+
+	// TransferredFiles is the number of files transferred so far (xfr#).
+	TransferredFiles int64
+	// ToCheck and ToCheckTotal are the "to-chk=ToCheck/ToCheckTotal"
+	// values: the number of files still to be checked, and the total
+	// number of files known so far. Older rsync versions print
+	// "ir-chk=" instead of "to-chk=", with the same meaning.
+	ToCheck      int64
+	ToCheckTotal int64
+}
+
+// The trailing "(xfr#N, to-chk=M/T)" group is present on every
+// --info=progress2 update, but with --progress only on the line that marks a
+// file as complete; the intermediate lines --progress redraws via "\r" while
+// a file is still being sent have no parenthetical at all, so that whole
+// group is optional.
+var progressRe = regexp.MustCompile(`^\s*([0-9,]+)\s+(\d+)%\s+([0-9.]+)(K|M|G|T)?B/s\s+(\d+):(\d{2}):(\d{2})(?:\s+\((?:xfr#(\d+), )?(?:to-chk|ir-chk)=(\d+)/(\d+)\))?\s*$`)
+
+// unitMultiplier returns the multiplier for the single-letter suffix rsync
+// prints in its --progress rate column, per rsync's do_big_num: 1024-based.
+func unitMultiplier(suffix string) float64 {
+	switch suffix {
+	case "K":
+		return 1024
+	case "M":
+		return 1024 * 1024
+	case "G":
+		return 1024 * 1024 * 1024
+	case "T":
+		return 1024 * 1024 * 1024 * 1024
+	default:
+		return 1
+	}
+}
+
+// parseProgressLine attempts to parse line as a progress update. It reports
+// whether line matched the progress line shape.
+func parseProgressLine(line string) (Event, bool, error) {
+	matches := progressRe.FindStringSubmatch(line)
+	if matches == nil {
+		return Event{}, false, nil
+	}
+
+	var ev Event
+	var err error
+	ev.BytesSoFar, err = parseCommaInt(matches[1])
+	if err != nil {
+		return Event{}, false, err
+	}
+	percent, err := parseCommaInt(matches[2])
+	if err != nil {
+		return Event{}, false, err
+	}
+	ev.Percent = int(percent)
+	rate, err := parseCommaFloat(matches[3])
+	if err != nil {
+		return Event{}, false, err
+	}
+	ev.BytesPerSec = rate * unitMultiplier(matches[4])
+	hours, err := parseCommaInt(matches[5])
+	if err != nil {
+		return Event{}, false, err
+	}
+	minutes, err := parseCommaInt(matches[6])
+	if err != nil {
+		return Event{}, false, err
+	}
+	seconds, err := parseCommaInt(matches[7])
+	if err != nil {
+		return Event{}, false, err
+	}
+	ev.ETA = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	if matches[8] != "" {
+		ev.TransferredFiles, err = parseCommaInt(matches[8])
+		if err != nil {
+			return Event{}, false, err
+		}
+	}
+	// The "(... to-chk=M/T)" group as a whole is absent on the
+	// intermediate lines --progress redraws while a file is still being
+	// sent; ToCheck/ToCheckTotal stay zero for those.
+	if matches[9] != "" {
+		ev.ToCheck, err = parseCommaInt(matches[9])
+		if err != nil {
+			return Event{}, false, err
+		}
+		ev.ToCheckTotal, err = parseCommaInt(matches[10])
+		if err != nil {
+			return Event{}, false, err
+		}
+	}
+	return ev, true, nil
+}
+
+// ParseStream incrementally decodes rsync output that may contain progress
+// updates from --info=progress2 or --progress, in addition to the usual
+// --verbose/--stats summary lines. Unlike Parse, ParseStream splits records
+// on both "\r" (used by rsync to redraw the current progress line in place)
+// and "\n", so cb is invoked once per progress update as the transfer runs.
+// The final Stats, extracted from the summary lines at the end of the
+// output, are returned once r is exhausted.
+func ParseStream(r io.Reader, cb func(Event)) (*Stats, error) {
+	p := &Stats{}
+	scan := bufio.NewScanner(r)
+	scan.Split(scanLinesOrCR)
+	for scan.Scan() {
+		line := scan.Text()
+		ev, ok, err := parseProgressLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if cb != nil {
+				cb(ev)
+			}
+			continue
+		}
+		if err := p.parseLine(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scan.Err(); err != nil {
+		if err == io.EOF {
+			return p, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// scanLinesOrCR is a bufio.SplitFunc like bufio.ScanLines, except it also
+// splits records on a bare "\r" (without a following "\n"), as used by
+// rsync to redraw --progress/--info=progress2 output in place. As with
+// bufio.ScanLines, the last non-terminated record is returned at EOF.
+func scanLinesOrCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[0:i], nil
+		}
+		return i + 1, data[0:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,149 @@
+package rsyncparse
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStream(t *testing.T) {
+	// Captured (abridged) --info=progress2 output: per-update lines
+	// separated by "\r", final summary separated by "\n" as usual.
+	input := "" +
+		"        32,768   7%    3.20MB/s    0:00:02 (xfr#1, to-chk=1/2)\r" +
+		"    1,048,576  99%   10.45MB/s    0:00:00 (xfr#1, to-chk=1/2)\r" +
+		"    1,048,576 100%   10.45MB/s    0:00:00 (xfr#1, to-chk=0/2)\n" +
+		"\n" +
+		"sent 1,100 bytes  received 35 bytes  756.67 bytes/sec\n" +
+		"total size is 1,048,576  speedup is 923.52\n"
+
+	var events []Event
+	stats, err := ParseStream(strings.NewReader(input), func(ev Event) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	last := events[2]
+	if got, want := last.BytesSoFar, int64(1048576); got != want {
+		t.Errorf("last.BytesSoFar = %d, want %d", got, want)
+	}
+	if got, want := last.Percent, 100; got != want {
+		t.Errorf("last.Percent = %d, want %d", got, want)
+	}
+	if got, want := last.BytesPerSec, 10.45*1024*1024; got != want {
+		t.Errorf("last.BytesPerSec = %v, want %v", got, want)
+	}
+	if got, want := last.ETA, time.Duration(0); got != want {
+		t.Errorf("last.ETA = %v, want %v", got, want)
+	}
+	if got, want := last.TransferredFiles, int64(1); got != want {
+		t.Errorf("last.TransferredFiles = %d, want %d", got, want)
+	}
+	if got, want := last.ToCheck, int64(0); got != want {
+		t.Errorf("last.ToCheck = %d, want %d", got, want)
+	}
+	if got, want := last.ToCheckTotal, int64(2); got != want {
+		t.Errorf("last.ToCheckTotal = %d, want %d", got, want)
+	}
+
+	if !stats.Found {
+		t.Fatal("stats.Found = false, want true")
+	}
+	if got, want := stats.TotalWritten, int64(1100); got != want {
+		t.Errorf("stats.TotalWritten = %d, want %d", got, want)
+	}
+	if got, want := stats.TotalSize, int64(1048576); got != want {
+		t.Errorf("stats.TotalSize = %d, want %d", got, want)
+	}
+}
+
+func TestParseStreamPlainProgress(t *testing.T) {
+	// Captured (abridged) classic --progress output for a single file:
+	// the intermediate, "\r"-redrawn lines have no trailing
+	// "(xfr#N, to-chk=M/T)" group at all; only the line marking the file
+	// complete does.
+	input := "" +
+		"        32,768   7%    3.20MB/s    0:00:02\r" +
+		"       524,288  50%    8.01MB/s    0:00:01\r" +
+		"     1,048,576 100%   10.45MB/s    0:00:00 (xfr#1, to-chk=0/1)\n" +
+		"\n" +
+		"sent 1,100 bytes  received 35 bytes  756.67 bytes/sec\n" +
+		"total size is 1,048,576  speedup is 923.52\n"
+
+	var events []Event
+	stats, err := ParseStream(strings.NewReader(input), func(ev Event) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+
+	first := events[0]
+	if got, want := first.BytesSoFar, int64(32768); got != want {
+		t.Errorf("first.BytesSoFar = %d, want %d", got, want)
+	}
+	if got, want := first.Percent, 7; got != want {
+		t.Errorf("first.Percent = %d, want %d", got, want)
+	}
+	if got, want := first.TransferredFiles, int64(0); got != want {
+		t.Errorf("first.TransferredFiles = %d, want %d", got, want)
+	}
+	if got, want := first.ToCheck, int64(0); got != want {
+		t.Errorf("first.ToCheck = %d, want %d", got, want)
+	}
+	if got, want := first.ToCheckTotal, int64(0); got != want {
+		t.Errorf("first.ToCheckTotal = %d, want %d", got, want)
+	}
+
+	last := events[2]
+	if got, want := last.TransferredFiles, int64(1); got != want {
+		t.Errorf("last.TransferredFiles = %d, want %d", got, want)
+	}
+	if got, want := last.ToCheckTotal, int64(1); got != want {
+		t.Errorf("last.ToCheckTotal = %d, want %d", got, want)
+	}
+
+	if !stats.Found {
+		t.Fatal("stats.Found = false, want true")
+	}
+}
+
+func TestScanLinesOrCR(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"bare CR", "a\rb\rc", []string{"a", "b", "c"}},
+		{"bare LF", "a\nb\nc\n", []string{"a", "b", "c"}},
+		{"CRLF", "a\r\nb\r\n", []string{"a", "b"}},
+		{"mixed", "a\rb\nc", []string{"a", "b", "c"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			scan := bufio.NewScanner(strings.NewReader(tt.input))
+			scan.Split(scanLinesOrCR)
+			for scan.Scan() {
+				got = append(got, scan.Text())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("token %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}